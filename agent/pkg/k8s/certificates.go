@@ -2,12 +2,15 @@ package k8s
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"io"
 	"net"
+	"sort"
 	"time"
 
 	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
@@ -25,36 +28,109 @@ const (
 	linkerdTrustDomainEnvVarName = "_l5d_trustdomain"
 )
 
-func (c *Client) GetControlPlaneCerts() (*pb.ControlPlaneCerts, error) {
-	identityPod, err := c.getControlPlaneComponentPod(identityComponentName)
+// GetControlPlaneCerts collects the trust anchors and issuer cert chain
+// served by every running linkerd-identity replica. In a single-replica
+// control plane this is just the one pod's certs; in HA it aggregates all
+// replicas and flags Divergent when a replica is serving an issuer chain
+// that doesn't match the rest (e.g. a botched rolling cert-manager renewal).
+// The flat IssuerCrtChain/Roots fields are populated from the majority
+// replica for callers that don't care about per-replica detail.
+func (c *Client) GetControlPlaneCerts(ctx context.Context) (*pb.ControlPlaneCertsHA, error) {
+	identityPods, err := c.getControlPlaneComponentPods(identityComponentName)
 	if err != nil {
 		return nil, err
 	}
 
-	container, err := getProxyContainer(identityPod)
-	if err != nil {
-		return nil, err
+	type replicaCerts struct {
+		replica *pb.ControlPlaneCertsReplica
+		hash    [sha256.Size]byte
 	}
 
-	rootCerts, err := extractRootsCerts(container)
-	if err != nil {
-		return nil, err
+	var collected []replicaCerts
+
+	for _, pod := range identityPods {
+		container, err := getProxyContainer(pod)
+		if err != nil {
+			c.log.Errorf("error getting proxy container for pod %s/%s: %s", pod.Namespace, pod.Name, err)
+			continue
+		}
+
+		rootCerts, err := extractRootsCerts(container)
+		if err != nil {
+			c.log.Errorf("error extracting roots for pod %s/%s: %s", pod.Namespace, pod.Name, err)
+			continue
+		}
+
+		issuerCerts, err := c.issuerSourceFor(pod).FetchIssuerChain(ctx, pod, container)
+		if err != nil {
+			c.log.Errorf("error extracting issuer chain for pod %s/%s: %s", pod.Namespace, pod.Name, err)
+			continue
+		}
+
+		hash := sha256.Sum256(issuerCerts.Raw)
+
+		collected = append(collected, replicaCerts{
+			replica: &pb.ControlPlaneCertsReplica{
+				PodName:     pod.Name,
+				PodIP:       pod.Status.PodIP,
+				IssuerChain: issuerCerts,
+				Roots:       rootCerts,
+			},
+			hash: hash,
+		})
 	}
 
-	issuerCerts, err := extractIssuerCertChain(identityPod, container)
-	if err != nil {
-		return nil, err
+	if len(collected) == 0 {
+		return nil, fmt.Errorf("could not extract certs from any running linkerd-%s pod", identityComponentName)
 	}
 
-	cpCerts := &pb.ControlPlaneCerts{
-		IssuerCrtChain: issuerCerts,
-		Roots:          rootCerts,
+	cpCerts := &pb.ControlPlaneCertsHA{}
+
+	// Count occurrences of each hash in first-seen order (rather than
+	// ranging over a map, whose iteration order is randomized) so that an
+	// even split across replicas picks the same majority on every call
+	// instead of flapping between polls.
+	type hashCount struct {
+		hash  [sha256.Size]byte
+		count int
+	}
+	var order []hashCount
+	for _, rc := range collected {
+		found := false
+		for i := range order {
+			if order[i].hash == rc.hash {
+				order[i].count++
+				found = true
+				break
+			}
+		}
+		if !found {
+			order = append(order, hashCount{hash: rc.hash, count: 1})
+		}
+	}
+
+	majorityHash := order[0].hash
+	majorityCount := order[0].count
+	for _, hc := range order[1:] {
+		if hc.count > majorityCount {
+			majorityHash = hc.hash
+			majorityCount = hc.count
+		}
+	}
+	cpCerts.Divergent = len(order) > 1
+
+	for _, rc := range collected {
+		cpCerts.Replicas = append(cpCerts.Replicas, rc.replica)
+		if rc.hash == majorityHash && cpCerts.IssuerCrtChain == nil {
+			cpCerts.IssuerCrtChain = rc.replica.IssuerChain
+			cpCerts.Roots = rc.replica.Roots
+		}
 	}
 
 	return cpCerts, nil
 }
 
-func (c *Client) getControlPlaneComponentPod(component string) (*v1.Pod, error) {
+func (c *Client) getControlPlaneComponentPods(component string) ([]*v1.Pod, error) {
 	selector := labels.Set(map[string]string{
 		controlPlaneComponentLabel: component,
 	}).AsSelector()
@@ -69,13 +145,25 @@ func (c *Client) getControlPlaneComponentPod(component string) (*v1.Pod, error)
 		return nil, fmt.Errorf("could not find linkerd-%s pod", component)
 	}
 
+	var running []*v1.Pod
 	for _, p := range pods {
 		if p.Status.Phase == v1.PodRunning {
-			return p, nil
+			running = append(running, p)
 		}
 	}
 
-	return nil, fmt.Errorf("could not find running pod for linkerd-%s", component)
+	if len(running) == 0 {
+		return nil, fmt.Errorf("could not find running pod for linkerd-%s", component)
+	}
+
+	// A lister's List() iterates an internal map-backed store, so its order
+	// isn't stable across calls. Sort by name so callers that rely on a
+	// stable pod order (majority-hash tie-breaking, tests) get one.
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].Name < running[j].Name
+	})
+
+	return running, nil
 }
 
 func getProxyContainer(pod *v1.Pod) (*v1.Container, error) {
@@ -138,7 +226,11 @@ func extractRootsCerts(container *v1.Container) (*pb.CertData, error) {
 	}, nil
 }
 
-func extractIssuerCertChain(pod *v1.Pod, container *v1.Container) (*pb.CertData, error) {
+// defaultDialTimeout bounds how long extractIssuerCertChain waits to
+// establish the TLS connection when ctx carries no deadline of its own.
+const defaultDialTimeout = 5 * time.Second
+
+func extractIssuerCertChain(ctx context.Context, pod *v1.Pod, container *v1.Container) (*pb.CertData, error) {
 	port, err := getProxyAdminPort(container)
 	if err != nil {
 		return nil, err
@@ -149,20 +241,27 @@ func extractIssuerCertChain(pod *v1.Pod, container *v1.Container) (*pb.CertData,
 		return nil, err
 	}
 
-	dialer := new(net.Dialer)
-	dialer.Timeout = 5 * time.Second
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultDialTimeout)
+		defer cancel()
+	}
 
-	conn, err := tls.DialWithDialer(
-		dialer,
-		"tcp",
-		fmt.Sprintf("%s:%d", pod.Status.PodIP, port), &tls.Config{
-			InsecureSkipVerify: true,
-			ServerName:         sn,
-		})
+	rawConn, err := (&net.Dialer{}).DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", pod.Status.PodIP, port))
 	if err != nil {
 		return nil, err
 	}
 
+	conn := tls.Client(rawConn, &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         sn,
+	})
+	defer conn.Close()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+
 	// skip the end cert
 	certs := conn.ConnectionState().PeerCertificates
 	if len(certs) < 2 {
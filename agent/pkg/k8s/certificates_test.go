@@ -1,11 +1,13 @@
 package k8s
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 	"time"
 
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
 	"github.com/linkerd/linkerd2/pkg/identity"
 	ldConsts "github.com/linkerd/linkerd2/pkg/k8s"
 	v1 "k8s.io/api/core/v1"
@@ -13,11 +15,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
-func TestFindIdentityPod(t *testing.T) {
+func TestFindIdentityPods(t *testing.T) {
 	fixtures := []*struct {
-		testName    string
-		pods        []runtime.Object
-		expectedErr error
+		testName     string
+		pods         []runtime.Object
+		expectedErr  error
+		expectedPods []string
 	}{
 		{
 			"can find identity pod",
@@ -42,6 +45,50 @@ func TestFindIdentityPod(t *testing.T) {
 				},
 			},
 			nil,
+			[]string{"linkerd-identity"},
+		},
+		{
+			"can find all running identity pods in HA",
+			[]runtime.Object{
+				&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "linkerd-identity-1",
+						Namespace: "linkerd",
+						Labels: map[string]string{
+							ldConsts.ControllerComponentLabel: identityComponentName,
+						},
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodRunning,
+					},
+				},
+				&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "linkerd-identity-2",
+						Namespace: "linkerd",
+						Labels: map[string]string{
+							ldConsts.ControllerComponentLabel: identityComponentName,
+						},
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodRunning,
+					},
+				},
+				&v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "linkerd-identity-3",
+						Namespace: "linkerd",
+						Labels: map[string]string{
+							ldConsts.ControllerComponentLabel: identityComponentName,
+						},
+					},
+					Status: v1.PodStatus{
+						Phase: v1.PodPending,
+					},
+				},
+			},
+			nil,
+			[]string{"linkerd-identity-1", "linkerd-identity-2"},
 		},
 		{
 			"cannot find a running identitiy pod",
@@ -66,6 +113,7 @@ func TestFindIdentityPod(t *testing.T) {
 				},
 			},
 			errors.New("could not find running pod for linkerd-identity"),
+			nil,
 		},
 		{
 			"cannot find a running identitiy pod",
@@ -78,6 +126,7 @@ func TestFindIdentityPod(t *testing.T) {
 				},
 			},
 			errors.New("could not find linkerd-identity pod"),
+			nil,
 		},
 	}
 
@@ -88,14 +137,19 @@ func TestFindIdentityPod(t *testing.T) {
 			c.Sync(nil, time.Second)
 			client := NewClient(c.sharedInformers)
 
-			pod, err := client.getControlPlaneComponentPod(identityComponentName)
+			pods, err := client.getControlPlaneComponentPods(identityComponentName)
 			if tc.expectedErr != nil {
 				if tc.expectedErr.Error() != err.Error() {
 					t.Fatalf("exepected err %s, got %s", tc.expectedErr, err)
 				}
 			} else {
-				if pod.Name != "linkerd-identity" {
-					t.Fatalf("exepected pod with name linkerd-identity, got %s", pod.Name)
+				if len(pods) != len(tc.expectedPods) {
+					t.Fatalf("exepected %d pods, got %d", len(tc.expectedPods), len(pods))
+				}
+				for i, p := range pods {
+					if p.Name != tc.expectedPods[i] {
+						t.Fatalf("exepected pod with name %s, got %s", tc.expectedPods[i], p.Name)
+					}
 				}
 			}
 		})
@@ -359,3 +413,108 @@ func TestExtractRootCerts(t *testing.T) {
 		})
 	}
 }
+
+// issuerChainByPod is a test IssuerSource that returns different issuer
+// chain data per pod name, so replica divergence can be simulated without
+// dialing anything.
+type issuerChainByPod map[string]*pb.CertData
+
+func (s issuerChainByPod) FetchIssuerChain(_ context.Context, pod *v1.Pod, _ *v1.Container) (*pb.CertData, error) {
+	return s[pod.Name], nil
+}
+
+func identityReplicaFixture(name string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "linkerd",
+			Labels: map[string]string{
+				ldConsts.ControllerComponentLabel: identityComponentName,
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: linkerdProxyContainerName,
+					Env: []v1.EnvVar{
+						{Name: linkerdRootsEnvVarName, Value: "roots"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGetControlPlaneCertsMajorityAndDivergence(t *testing.T) {
+	fixtures := []*struct {
+		testName          string
+		chainsByPod       issuerChainByPod
+		expectedDivergent bool
+		expectedChain     string
+	}{
+		{
+			"all replicas agree",
+			issuerChainByPod{
+				"linkerd-identity-1": {Raw: []byte("chain-a")},
+				"linkerd-identity-2": {Raw: []byte("chain-a")},
+				"linkerd-identity-3": {Raw: []byte("chain-a")},
+			},
+			false,
+			"chain-a",
+		},
+		{
+			"one replica diverges from the rest",
+			issuerChainByPod{
+				"linkerd-identity-1": {Raw: []byte("chain-a")},
+				"linkerd-identity-2": {Raw: []byte("chain-a")},
+				"linkerd-identity-3": {Raw: []byte("chain-b")},
+			},
+			true,
+			"chain-a",
+		},
+		{
+			"an even split keeps the first-seen chain by pod name",
+			issuerChainByPod{
+				"linkerd-identity-1": {Raw: []byte("chain-a")},
+				"linkerd-identity-2": {Raw: []byte("chain-b")},
+			},
+			true,
+			"chain-a",
+		},
+	}
+
+	for _, tc := range fixtures {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			var pods []runtime.Object
+			for name := range tc.chainsByPod {
+				pods = append(pods, identityReplicaFixture(name))
+			}
+
+			c := fakeClient(pods...)
+			c.Sync(nil, time.Second)
+			client := NewClient(c.sharedInformers)
+			client.SetIssuerSource(tc.chainsByPod)
+
+			cpCerts, err := client.GetControlPlaneCerts(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if cpCerts.Divergent != tc.expectedDivergent {
+				t.Fatalf("expected Divergent=%v, got %v", tc.expectedDivergent, cpCerts.Divergent)
+			}
+
+			if string(cpCerts.IssuerCrtChain.Raw) != tc.expectedChain {
+				t.Fatalf("expected flat issuer chain %q, got %q", tc.expectedChain, string(cpCerts.IssuerCrtChain.Raw))
+			}
+
+			if len(cpCerts.Replicas) != len(tc.chainsByPod) {
+				t.Fatalf("expected %d replicas, got %d", len(tc.chainsByPod), len(cpCerts.Replicas))
+			}
+		})
+	}
+}
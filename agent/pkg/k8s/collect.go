@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+)
+
+const (
+	// defaultCollectWorkers bounds how many targets CollectCerts dials
+	// concurrently, regardless of how many targets are passed in.
+	defaultCollectWorkers = 8
+
+	// defaultTargetTimeout is used for a Target that doesn't set its own
+	// Timeout.
+	defaultTargetTimeout = 5 * time.Second
+)
+
+// Target is one control-plane namespace or kubeconfig context to collect
+// certs from. Client is expected to already be scoped to that namespace or
+// context.
+type Target struct {
+	// Name labels this target in results and log lines, e.g. the control
+	// plane namespace or the kubeconfig context name.
+	Name    string
+	Client  *Client
+	Timeout time.Duration
+}
+
+// CollectCerts fans out GetControlPlaneCerts across targets using a bounded
+// worker pool, so scraping certs from many Linkerd multicluster control
+// planes or trust domains isn't O(N) sequential TLS dials. Unlike
+// GetControlPlaneCerts, a failure on one target doesn't abort the others:
+// every target's outcome lands in exactly one of the two returned maps,
+// keyed by Target.Name.
+func CollectCerts(ctx context.Context, targets []Target, workers int) (map[string]*pb.ControlPlaneCertsHA, map[string]error) {
+	certsByTarget := make(map[string]*pb.ControlPlaneCertsHA, len(targets))
+	errsByTarget := make(map[string]error)
+
+	if len(targets) == 0 {
+		return certsByTarget, errsByTarget
+	}
+
+	workers = clampWorkers(workers, len(targets))
+
+	jobs := make(chan Target)
+	results := make(chan collectResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				results <- collectOne(ctx, target)
+			}
+		}()
+	}
+
+	go func() {
+		for _, target := range targets {
+			jobs <- target
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		if r.err != nil {
+			errsByTarget[r.name] = r.err
+			continue
+		}
+		certsByTarget[r.name] = r.certs
+	}
+
+	return certsByTarget, errsByTarget
+}
+
+// clampWorkers bounds the requested worker count to [1, min(numTargets,
+// defaultCollectWorkers)]: a non-positive or oversized request falls back
+// to one worker per target, and any request is capped at
+// defaultCollectWorkers regardless of how many targets there are.
+func clampWorkers(workers, numTargets int) int {
+	if workers <= 0 || workers > numTargets {
+		workers = numTargets
+	}
+	if workers > defaultCollectWorkers {
+		workers = defaultCollectWorkers
+	}
+	return workers
+}
+
+// collectResult is one target's CollectCerts outcome.
+type collectResult struct {
+	name  string
+	certs *pb.ControlPlaneCertsHA
+	err   error
+}
+
+func collectOne(ctx context.Context, target Target) collectResult {
+	if target.Client == nil {
+		return collectResult{name: target.Name, err: fmt.Errorf("target %s has a nil Client", target.Name)}
+	}
+
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultTargetTimeout
+	}
+
+	targetCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log := target.Client.log.WithField("target", target.Name)
+
+	certs, err := target.Client.GetControlPlaneCerts(targetCtx)
+	if err != nil {
+		log.Errorf("error collecting control plane certs: %s", err)
+	} else {
+		log.Infof("collected control plane certs")
+	}
+
+	return collectResult{name: target.Name, certs: certs, err: err}
+}
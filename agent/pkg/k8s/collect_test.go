@@ -0,0 +1,123 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClampWorkers(t *testing.T) {
+	fixtures := []*struct {
+		testName     string
+		workers      int
+		numTargets   int
+		expectedPool int
+	}{
+		{"zero workers defaults to one per target", 0, 3, 3},
+		{"negative workers defaults to one per target", -1, 3, 3},
+		{"workers above target count is capped to target count", 20, 3, 3},
+		{"workers above defaultCollectWorkers is capped", 100, 100, defaultCollectWorkers},
+		{"workers within bounds is left alone", 2, 5, 2},
+	}
+
+	for _, tc := range fixtures {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			got := clampWorkers(tc.workers, tc.numTargets)
+			if got != tc.expectedPool {
+				t.Fatalf("expected %d workers, got %d", tc.expectedPool, got)
+			}
+		})
+	}
+}
+
+// stubIssuerSource lets tests exercise GetControlPlaneCerts without
+// actually dialing a proxy admin port.
+type stubIssuerSource struct {
+	data *pb.CertData
+	err  error
+}
+
+func (s stubIssuerSource) FetchIssuerChain(context.Context, *v1.Pod, *v1.Container) (*pb.CertData, error) {
+	return s.data, s.err
+}
+
+func identityPodFixture() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "linkerd-identity",
+			Namespace: "linkerd",
+			Labels: map[string]string{
+				controlPlaneComponentLabel: identityComponentName,
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			PodIP: "10.0.0.1",
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Name: linkerdProxyContainerName,
+					Env: []v1.EnvVar{
+						{Name: linkerdRootsEnvVarName, Value: "roots"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCollectCerts(t *testing.T) {
+	fakeOk := fakeClient(identityPodFixture())
+	fakeOk.Sync(nil, time.Second)
+	okClient := NewClient(fakeOk.sharedInformers)
+	okClient.SetIssuerSource(stubIssuerSource{data: &pb.CertData{Raw: []byte("issuer")}})
+
+	fakeFail := fakeClient()
+	fakeFail.Sync(nil, time.Second)
+	failClient := NewClient(fakeFail.sharedInformers)
+
+	targets := []Target{
+		{Name: "cluster-ok", Client: okClient},
+		{Name: "cluster-fail", Client: failClient},
+	}
+
+	certsByTarget, errsByTarget := CollectCerts(context.Background(), targets, 2)
+
+	if _, ok := certsByTarget["cluster-ok"]; !ok {
+		t.Fatalf("expected cluster-ok to have succeeded, errors: %v", errsByTarget)
+	}
+	if _, ok := errsByTarget["cluster-fail"]; !ok {
+		t.Fatalf("expected cluster-fail to have failed")
+	}
+	if len(certsByTarget) != 1 || len(errsByTarget) != 1 {
+		t.Fatalf("expected exactly one success and one failure, got certs=%v errs=%v", certsByTarget, errsByTarget)
+	}
+}
+
+func TestCollectCertsNilClient(t *testing.T) {
+	targets := []Target{
+		{Name: "cluster-nil-client", Client: nil},
+	}
+
+	certsByTarget, errsByTarget := CollectCerts(context.Background(), targets, 1)
+
+	if len(certsByTarget) != 0 {
+		t.Fatalf("expected no successes, got %v", certsByTarget)
+	}
+	if _, ok := errsByTarget["cluster-nil-client"]; !ok {
+		t.Fatalf("expected cluster-nil-client to surface as an error rather than panicking")
+	}
+}
+
+func TestCollectCertsNoTargets(t *testing.T) {
+	certsByTarget, errsByTarget := CollectCerts(context.Background(), nil, 4)
+	if len(certsByTarget) != 0 || len(errsByTarget) != 0 {
+		t.Fatalf("expected empty results for no targets, got certs=%v errs=%v", certsByTarget, errsByTarget)
+	}
+}
@@ -0,0 +1,214 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+	cmclientset "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	identityIssuerSecretName = "linkerd-identity-issuer"
+
+	certManagerIssuerNameAnnotation = "cert-manager.io/issuer-name"
+	certManagerIssuerKindAnnotation = "cert-manager.io/issuer-kind"
+	defaultCertManagerIssuerKind    = "Issuer"
+	clusterIssuerKind               = "ClusterIssuer"
+	defaultClusterResourceNamespace = "cert-manager"
+
+	// secretIssuerCrtKey and secretTLSCrtKey are preferred over
+	// secretIssuerCAKey: the issuer chain is what the identity pod actually
+	// serves, and ca.crt is the root trust anchor, not the issuer. Reading
+	// ca.crt first would report a self-signed root as the "issuer chain",
+	// masking a misconfigured/rotated issuer from the chunk0-2 diagnostics.
+	secretIssuerCrtKey = "crt.pem"
+	secretTLSCrtKey    = "tls.crt"
+	secretIssuerCAKey  = "ca.crt"
+)
+
+// IssuerSource fetches the issuer cert chain served by a linkerd-identity
+// pod. Linkerd supports several ways of provisioning the identity issuer, so
+// Client picks one concrete source per pod (see issuerSourceFor), dialing
+// the pod directly only when no issuer Secret exists. A source that fails is
+// not followed by another.
+type IssuerSource interface {
+	FetchIssuerChain(ctx context.Context, pod *v1.Pod, container *v1.Container) (*pb.CertData, error)
+}
+
+// dialIssuerSource extracts the issuer chain by dialing the proxy admin
+// port and reading the TLS peer certs off the wire. It works for every
+// install, but only reflects an in-process issuer: it can't see an issuer
+// provisioned out-of-band by cert-manager or an external PKI.
+type dialIssuerSource struct{}
+
+func (dialIssuerSource) FetchIssuerChain(ctx context.Context, pod *v1.Pod, container *v1.Container) (*pb.CertData, error) {
+	return extractIssuerCertChain(ctx, pod, container)
+}
+
+// SecretIssuerSource reads the issuer cert chain directly out of the
+// linkerd-identity-issuer Secret (or a user-specified name/namespace). This
+// is the source to use when identity.issuer.scheme is kubernetes.io/tls.
+type SecretIssuerSource struct {
+	secretLister corelisters.SecretLister
+	name         string
+	namespace    string
+}
+
+// NewSecretIssuerSource returns a SecretIssuerSource reading the named
+// Secret. An empty name defaults to linkerd-identity-issuer; an empty
+// namespace defaults to the identity pod's own namespace.
+func NewSecretIssuerSource(secretLister corelisters.SecretLister, name, namespace string) *SecretIssuerSource {
+	if name == "" {
+		name = identityIssuerSecretName
+	}
+
+	return &SecretIssuerSource{
+		secretLister: secretLister,
+		name:         name,
+		namespace:    namespace,
+	}
+}
+
+func (s *SecretIssuerSource) FetchIssuerChain(_ context.Context, pod *v1.Pod, _ *v1.Container) (*pb.CertData, error) {
+	ns := s.namespace
+	if ns == "" {
+		ns = pod.Namespace
+	}
+
+	secret, err := s.secretLister.Secrets(ns).Get(s.name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting secret %s/%s: %w", ns, s.name, err)
+	}
+
+	return issuerChainFromSecretData(secret)
+}
+
+// CertManagerIssuerSource follows the cert-manager.io/issuer-name annotation
+// on the identity issuer Secret to the Issuer or ClusterIssuer it names, and
+// reads the CA bundle Secret that issuer was configured with. This is what
+// lets buoyant report the issuing CA's actual chain, which can differ from
+// whatever is sitting in the identity Secret (e.g. mid cert-manager
+// renewal).
+type CertManagerIssuerSource struct {
+	secretLister corelisters.SecretLister
+	cmClient     cmclientset.Interface
+	name         string
+
+	// clusterResourceNamespace is where ClusterIssuer CA bundle Secrets
+	// live; it mirrors cert-manager's --cluster-resource-namespace flag.
+	clusterResourceNamespace string
+}
+
+// NewCertManagerIssuerSource returns a CertManagerIssuerSource reading the
+// named Secret. An empty name defaults to linkerd-identity-issuer.
+func NewCertManagerIssuerSource(secretLister corelisters.SecretLister, cmClient cmclientset.Interface, name string) *CertManagerIssuerSource {
+	if name == "" {
+		name = identityIssuerSecretName
+	}
+
+	return &CertManagerIssuerSource{
+		secretLister:             secretLister,
+		cmClient:                 cmClient,
+		name:                     name,
+		clusterResourceNamespace: defaultClusterResourceNamespace,
+	}
+}
+
+func (s *CertManagerIssuerSource) FetchIssuerChain(ctx context.Context, pod *v1.Pod, _ *v1.Container) (*pb.CertData, error) {
+	secret, err := s.secretLister.Secrets(pod.Namespace).Get(s.name)
+	if err != nil {
+		return nil, fmt.Errorf("error getting secret %s/%s: %w", pod.Namespace, s.name, err)
+	}
+
+	issuerName := secret.Annotations[certManagerIssuerNameAnnotation]
+	if issuerName == "" {
+		return nil, fmt.Errorf("secret %s/%s is not managed by cert-manager: missing %s annotation", secret.Namespace, secret.Name, certManagerIssuerNameAnnotation)
+	}
+
+	issuerKind := secret.Annotations[certManagerIssuerKindAnnotation]
+	if issuerKind == "" {
+		issuerKind = defaultCertManagerIssuerKind
+	}
+
+	caSecretNamespace, caSecretName, err := s.resolveIssuerCASecret(ctx, issuerKind, issuerName, secret.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	caSecret, err := s.secretLister.Secrets(caSecretNamespace).Get(caSecretName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting issuer CA secret %s/%s: %w", caSecretNamespace, caSecretName, err)
+	}
+
+	return issuerChainFromSecretData(caSecret)
+}
+
+// resolveIssuerCASecret looks up the Issuer or ClusterIssuer named by
+// issuerName and returns the namespace/name of the Secret holding its CA
+// bundle. A ClusterIssuer's CA secret lives in clusterResourceNamespace; an
+// Issuer's lives alongside it in secretNamespace.
+func (s *CertManagerIssuerSource) resolveIssuerCASecret(ctx context.Context, issuerKind, issuerName, secretNamespace string) (string, string, error) {
+	if issuerKind == clusterIssuerKind {
+		issuer, err := s.cmClient.CertmanagerV1().ClusterIssuers().Get(ctx, issuerName, metav1.GetOptions{})
+		if err != nil {
+			return "", "", fmt.Errorf("error getting ClusterIssuer %s: %w", issuerName, err)
+		}
+		if issuer.Spec.CA == nil || issuer.Spec.CA.SecretName == "" {
+			return "", "", fmt.Errorf("ClusterIssuer %s has no CA secret configured", issuerName)
+		}
+		return s.clusterResourceNamespace, issuer.Spec.CA.SecretName, nil
+	}
+
+	issuer, err := s.cmClient.CertmanagerV1().Issuers(secretNamespace).Get(ctx, issuerName, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("error getting Issuer %s/%s: %w", secretNamespace, issuerName, err)
+	}
+	if issuer.Spec.CA == nil || issuer.Spec.CA.SecretName == "" {
+		return "", "", fmt.Errorf("Issuer %s/%s has no CA secret configured", secretNamespace, issuerName)
+	}
+	return secretNamespace, issuer.Spec.CA.SecretName, nil
+}
+
+func issuerChainFromSecretData(secret *v1.Secret) (*pb.CertData, error) {
+	for _, key := range []string{secretIssuerCrtKey, secretTLSCrtKey, secretIssuerCAKey} {
+		if crt, ok := secret.Data[key]; ok && len(crt) > 0 {
+			return &pb.CertData{Raw: crt}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("secret %s/%s does not contain a recognized issuer cert key", secret.Namespace, secret.Name)
+}
+
+// issuerSourceFor auto-detects which IssuerSource to use for pod: an
+// explicit override set via SetIssuerSource, else the identity issuer
+// Secret if it's annotated by cert-manager and a cert-manager client is
+// configured, else the Secret's own contents,
+// falling back to dialing the pod when no issuer Secret exists at all.
+func (c *Client) issuerSourceFor(pod *v1.Pod) IssuerSource {
+	if c.issuerSourceOverride != nil {
+		return c.issuerSourceOverride
+	}
+
+	secretLister := c.sharedInformers.Core().V1().Secrets().Lister()
+	secret, err := secretLister.Secrets(pod.Namespace).Get(identityIssuerSecretName)
+	if err != nil {
+		return dialIssuerSource{}
+	}
+
+	if secret.Annotations[certManagerIssuerNameAnnotation] != "" && c.cmClient != nil {
+		return NewCertManagerIssuerSource(secretLister, c.cmClient, identityIssuerSecretName)
+	}
+
+	return NewSecretIssuerSource(secretLister, identityIssuerSecretName, "")
+}
+
+// SetIssuerSource overrides issuer source auto-detection with source for
+// every subsequent GetControlPlaneCerts call. Pass nil to restore
+// auto-detection.
+func (c *Client) SetIssuerSource(source IssuerSource) {
+	c.issuerSourceOverride = source
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestIssuerChainFromSecretData(t *testing.T) {
+	fixtures := []*struct {
+		testName    string
+		data        map[string][]byte
+		expectedRaw string
+		expectedErr error
+	}{
+		{
+			"prefers crt.pem over tls.crt and ca.crt",
+			map[string][]byte{
+				secretIssuerCrtKey: []byte("crt.pem data"),
+				secretTLSCrtKey:    []byte("tls.crt data"),
+				secretIssuerCAKey:  []byte("ca.crt data"),
+			},
+			"crt.pem data",
+			nil,
+		},
+		{
+			"prefers tls.crt over ca.crt when crt.pem is absent",
+			map[string][]byte{
+				secretTLSCrtKey:   []byte("tls.crt data"),
+				secretIssuerCAKey: []byte("ca.crt data"),
+			},
+			"tls.crt data",
+			nil,
+		},
+		{
+			"falls back to ca.crt when nothing else is present",
+			map[string][]byte{
+				secretIssuerCAKey: []byte("ca.crt data"),
+			},
+			"ca.crt data",
+			nil,
+		},
+		{
+			"no recognized key",
+			map[string][]byte{
+				"unrelated": []byte("data"),
+			},
+			"",
+			fmt.Errorf("secret some-ns/some-secret does not contain a recognized issuer cert key"),
+		},
+	}
+
+	for _, tc := range fixtures {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-secret",
+					Namespace: "some-ns",
+				},
+				Data: tc.data,
+			}
+
+			certData, err := issuerChainFromSecretData(secret)
+			if tc.expectedErr != nil {
+				if err == nil || tc.expectedErr.Error() != err.Error() {
+					t.Fatalf("expected err %s, got %s", tc.expectedErr, err)
+				}
+				return
+			}
+
+			if string(certData.Raw) != tc.expectedRaw {
+				t.Fatalf("expected raw %q, got %q", tc.expectedRaw, string(certData.Raw))
+			}
+		})
+	}
+}
+
+func TestIssuerSourceForAutoDetection(t *testing.T) {
+	identityPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "linkerd-identity",
+			Namespace: "linkerd",
+		},
+	}
+
+	fixtures := []*struct {
+		testName     string
+		objects      []runtime.Object
+		expectedType interface{}
+	}{
+		{
+			"no issuer secret falls back to dialing",
+			nil,
+			dialIssuerSource{},
+		},
+		{
+			"issuer secret without cert-manager annotation uses SecretIssuerSource",
+			[]runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      identityIssuerSecretName,
+						Namespace: "linkerd",
+					},
+				},
+			},
+			&SecretIssuerSource{},
+		},
+		{
+			"cert-manager annotation without a wired cmClient falls back to SecretIssuerSource",
+			[]runtime.Object{
+				&v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      identityIssuerSecretName,
+						Namespace: "linkerd",
+						Annotations: map[string]string{
+							certManagerIssuerNameAnnotation: "ca-issuer",
+						},
+					},
+				},
+			},
+			&SecretIssuerSource{},
+		},
+	}
+
+	for _, tc := range fixtures {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			c := fakeClient(tc.objects...)
+			c.Sync(nil, time.Second)
+			client := NewClient(c.sharedInformers)
+
+			source := client.issuerSourceFor(identityPod)
+
+			switch tc.expectedType.(type) {
+			case dialIssuerSource:
+				if _, ok := source.(dialIssuerSource); !ok {
+					t.Fatalf("expected dialIssuerSource, got %T", source)
+				}
+			case *SecretIssuerSource:
+				if _, ok := source.(*SecretIssuerSource); !ok {
+					t.Fatalf("expected *SecretIssuerSource, got %T", source)
+				}
+			}
+		})
+	}
+}
+
+func TestIssuerSourceForOverride(t *testing.T) {
+	c := fakeClient()
+	c.Sync(nil, time.Second)
+	client := NewClient(c.sharedInformers)
+
+	override := dialIssuerSource{}
+	client.SetIssuerSource(override)
+
+	source := client.issuerSourceFor(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "linkerd-identity", Namespace: "linkerd"},
+	})
+	if _, ok := source.(dialIssuerSource); !ok {
+		t.Fatalf("expected override to be returned, got %T", source)
+	}
+}
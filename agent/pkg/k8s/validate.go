@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+)
+
+const (
+	certExpiryWarningWindow = 30 * 24 * time.Hour
+	minRSAKeyBits           = 2048
+)
+
+// ValidateControlPlaneCerts decodes the roots and issuer chain contained in
+// cpCerts and returns per-cert diagnostics: identity, validity window,
+// algorithm strength, key identifiers, and, for issuer certs, whether the
+// cert is trusted by the supplied trust anchors (TrustedByRoots is left
+// false on the roots' own diagnostics). It's meant to be reported to bcloud so
+// operators get proactive rotation alerts instead of a post-mortem when
+// mTLS breaks.
+func ValidateControlPlaneCerts(cpCerts *pb.ControlPlaneCertsHA) (*pb.CertDiagnostics, error) {
+	if cpCerts == nil {
+		return nil, fmt.Errorf("control plane certs cannot be nil")
+	}
+
+	roots, err := decodeCertsPEM(cpCerts.Roots)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding roots: %w", err)
+	}
+
+	issuers, err := decodeCertsPEM(cpCerts.IssuerCrtChain)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding issuer chain: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	for _, root := range roots {
+		pool.AddCert(root)
+	}
+
+	diagnostics := &pb.CertDiagnostics{}
+	for _, root := range roots {
+		d := diagnoseCert(root, nil, nil)
+		if !root.IsCA {
+			d.Warnings = append(d.Warnings, "root cert does not have IsCA=true")
+		}
+		if root.KeyUsage&x509.KeyUsageCertSign == 0 {
+			d.Warnings = append(d.Warnings, "root cert does not have KeyUsage=CertSign")
+		}
+		diagnostics.Roots = append(diagnostics.Roots, d)
+	}
+
+	// Each issuer cert is verified against every other cert in the chain as
+	// a potential intermediate, not just the roots: a sub-CA chained
+	// through another intermediate up to the root is a realistic shape for
+	// the external-PKI/cert-manager issuer sources, and without this every
+	// cert below the top-level one would be wrongly flagged untrusted.
+	for i, issuer := range issuers {
+		intermediates := x509.NewCertPool()
+		for j, other := range issuers {
+			if j != i {
+				intermediates.AddCert(other)
+			}
+		}
+
+		d := diagnoseCert(issuer, pool, intermediates)
+		diagnostics.Issuers = append(diagnostics.Issuers, d)
+	}
+
+	return diagnostics, nil
+}
+
+func diagnoseCert(cert *x509.Certificate, trustRoots, intermediates *x509.CertPool) *pb.CertDiagnostic {
+	now := time.Now()
+
+	d := &pb.CertDiagnostic{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		Sans:               cert.DNSNames,
+		NotBefore:          cert.NotBefore.Unix(),
+		NotAfter:           cert.NotAfter.Unix(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SubjectKeyId:       hex.EncodeToString(cert.SubjectKeyId),
+		AuthorityKeyId:     hex.EncodeToString(cert.AuthorityKeyId),
+	}
+
+	d.RemainingLifetimeSeconds = int64(cert.NotAfter.Sub(now).Seconds())
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		d.PublicKeyAlgorithm = "RSA"
+		d.PublicKeyBits = int32(pub.N.BitLen())
+		if pub.N.BitLen() < minRSAKeyBits {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("weak RSA key: %d bits", pub.N.BitLen()))
+		}
+	case *ecdsa.PublicKey:
+		d.PublicKeyAlgorithm = "ECDSA " + pub.Curve.Params().Name
+		d.PublicKeyBits = int32(pub.Curve.Params().BitSize)
+		if pub.Curve.Params().Name != "P-256" && pub.Curve.Params().Name != "P-384" {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("non-standard ECDSA curve: %s", pub.Curve.Params().Name))
+		}
+	default:
+		d.PublicKeyAlgorithm = cert.PublicKeyAlgorithm.String()
+	}
+
+	if now.Before(cert.NotBefore) {
+		d.Warnings = append(d.Warnings, "cert is not yet valid")
+	}
+	if now.After(cert.NotAfter) {
+		d.Warnings = append(d.Warnings, "cert has expired")
+	} else if cert.NotAfter.Sub(now) < certExpiryWarningWindow {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("cert expires within %s", certExpiryWarningWindow))
+	}
+
+	if trustRoots != nil {
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         trustRoots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		d.TrustedByRoots = err == nil
+		if err != nil {
+			d.Warnings = append(d.Warnings, fmt.Sprintf("issuer chain not linked to any provided root: %s", err))
+		}
+	}
+
+	return d
+}
+
+func decodeCertsPEM(certData *pb.CertData) ([]*x509.Certificate, error) {
+	if certData == nil {
+		return nil, fmt.Errorf("cert data cannot be nil")
+	}
+
+	var certs []*x509.Certificate
+	rest := certData.Raw
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in PEM data")
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,375 @@
+package k8s
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+)
+
+func mustGenRSACert(t *testing.T, bits int, notBefore, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %s", err)
+	}
+
+	return mustSelfSignCert(t, &priv.PublicKey, priv, notBefore, notAfter)
+}
+
+func mustGenECDSACert(t *testing.T, curve elliptic.Curve, notBefore, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating ECDSA key: %s", err)
+	}
+
+	return mustSelfSignCert(t, &priv.PublicKey, priv, notBefore, notAfter)
+}
+
+func mustSelfSignCert(t *testing.T, pub interface{}, priv interface{}, notBefore, notAfter time.Time) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: mustSerial(t),
+		Subject:      pkix.Name{CommonName: "test.identity.linkerd"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatalf("error creating certificate: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %s", err)
+	}
+
+	return cert
+}
+
+// mustGenCACert generates a self-signed CA cert, for use as the root of a
+// chain built with mustSignCert.
+func mustGenCACert(t *testing.T, cn string, notBefore, notAfter time.Time) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          mustSerial(t),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("error creating CA certificate: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing CA certificate: %s", err)
+	}
+
+	return cert, priv
+}
+
+// mustSignCert issues a cert for cn, signed by parent/parentKey. Set isCA to
+// chain a further intermediate off of it.
+func mustSignCert(t *testing.T, parent *x509.Certificate, parentKey *rsa.PrivateKey, cn string, notBefore, notAfter time.Time, isCA bool) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: mustSerial(t),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &priv.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("error creating certificate: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %s", err)
+	}
+
+	return cert, priv
+}
+
+func mustSerial(t *testing.T) *big.Int {
+	t.Helper()
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("error generating serial number: %s", err)
+	}
+	return serial
+}
+
+func TestDiagnoseCert(t *testing.T) {
+	now := time.Now()
+
+	fixtures := []*struct {
+		testName    string
+		cert        *x509.Certificate
+		wantWarning string
+	}{
+		{
+			"healthy cert has no warnings",
+			mustGenRSACert(t, 2048, now.Add(-time.Hour), now.Add(365*24*time.Hour)),
+			"",
+		},
+		{
+			"expired cert",
+			mustGenRSACert(t, 2048, now.Add(-48*time.Hour), now.Add(-24*time.Hour)),
+			"cert has expired",
+		},
+		{
+			"not yet valid cert",
+			mustGenRSACert(t, 2048, now.Add(24*time.Hour), now.Add(48*time.Hour)),
+			"cert is not yet valid",
+		},
+		{
+			"cert expiring soon",
+			mustGenRSACert(t, 2048, now.Add(-time.Hour), now.Add(10*24*time.Hour)),
+			"cert expires within",
+		},
+		{
+			"weak RSA key",
+			mustGenRSACert(t, 1024, now.Add(-time.Hour), now.Add(365*24*time.Hour)),
+			"weak RSA key",
+		},
+		{
+			"non-standard ECDSA curve",
+			mustGenECDSACert(t, elliptic.P224(), now.Add(-time.Hour), now.Add(365*24*time.Hour)),
+			"non-standard ECDSA curve",
+		},
+	}
+
+	for _, tc := range fixtures {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			d := diagnoseCert(tc.cert, nil, nil)
+
+			if tc.wantWarning == "" {
+				if len(d.Warnings) != 0 {
+					t.Fatalf("expected no warnings, got %v", d.Warnings)
+				}
+				return
+			}
+
+			found := false
+			for _, w := range d.Warnings {
+				if strings.Contains(w, tc.wantWarning) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Fatalf("expected a warning containing %q, got %v", tc.wantWarning, d.Warnings)
+			}
+		})
+	}
+}
+
+func TestDiagnoseCertTrustedByRoots(t *testing.T) {
+	now := time.Now()
+	issuer := mustGenRSACert(t, 2048, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+
+	emptyPool := x509.NewCertPool()
+	d := diagnoseCert(issuer, emptyPool, nil)
+
+	if d.TrustedByRoots {
+		t.Fatalf("expected TrustedByRoots=false when issuer isn't signed by any provided root")
+	}
+
+	found := false
+	for _, w := range d.Warnings {
+		if strings.Contains(w, "not linked to any provided root") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("expected an untrusted-chain warning, got %v", d.Warnings)
+	}
+}
+
+func TestDiagnoseCertTrustedByRootsDirectlySigned(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(365*24*time.Hour)
+
+	root, rootKey := mustGenCACert(t, "root.linkerd.cluster.local", notBefore, notAfter)
+	issuer, _ := mustSignCert(t, root, rootKey, "identity.linkerd.cluster.local", notBefore, notAfter, true)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+
+	d := diagnoseCert(issuer, pool, nil)
+
+	if !d.TrustedByRoots {
+		t.Fatalf("expected TrustedByRoots=true when issuer is signed directly by a provided root, warnings: %v", d.Warnings)
+	}
+}
+
+func TestDiagnoseCertTrustedByRootsViaIntermediate(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(365*24*time.Hour)
+
+	root, rootKey := mustGenCACert(t, "root.linkerd.cluster.local", notBefore, notAfter)
+	intermediate, intermediateKey := mustSignCert(t, root, rootKey, "sub-ca.linkerd.cluster.local", notBefore, notAfter, true)
+	issuer, _ := mustSignCert(t, intermediate, intermediateKey, "identity.linkerd.cluster.local", notBefore, notAfter, true)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(root)
+
+	// Without the intermediate in the pool, the issuer can't be chained to
+	// the root and verification fails.
+	d := diagnoseCert(issuer, pool, nil)
+	if d.TrustedByRoots {
+		t.Fatalf("expected TrustedByRoots=false without the intermediate in the pool")
+	}
+
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+
+	d = diagnoseCert(issuer, pool, intermediates)
+	if !d.TrustedByRoots {
+		t.Fatalf("expected TrustedByRoots=true when the intermediate chains the issuer to a provided root, warnings: %v", d.Warnings)
+	}
+}
+
+func TestDecodeCertsPEM(t *testing.T) {
+	now := time.Now()
+	cert1 := mustGenRSACert(t, 2048, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	cert2 := mustGenRSACert(t, 2048, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+
+	t.Run("nil cert data is an error", func(t *testing.T) {
+		if _, err := decodeCertsPEM(nil); err == nil {
+			t.Fatalf("expected an error for nil cert data")
+		}
+	})
+
+	t.Run("invalid PEM is an error", func(t *testing.T) {
+		certData := &pb.CertData{Raw: []byte("not pem data")}
+		if _, err := decodeCertsPEM(certData); err == nil {
+			t.Fatalf("expected an error for non-PEM data")
+		}
+	})
+
+	t.Run("single cert decodes", func(t *testing.T) {
+		encoded, err := encodeCertificatesPEM(cert1)
+		if err != nil {
+			t.Fatalf("error encoding cert: %s", err)
+		}
+
+		certs, err := decodeCertsPEM(&pb.CertData{Raw: []byte(encoded)})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(certs) != 1 {
+			t.Fatalf("expected 1 cert, got %d", len(certs))
+		}
+	})
+
+	t.Run("multiple certs decode in order", func(t *testing.T) {
+		encoded, err := encodeCertificatesPEM(cert1, cert2)
+		if err != nil {
+			t.Fatalf("error encoding certs: %s", err)
+		}
+
+		certs, err := decodeCertsPEM(&pb.CertData{Raw: []byte(encoded)})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(certs) != 2 {
+			t.Fatalf("expected 2 certs, got %d", len(certs))
+		}
+		if certs[0].SerialNumber.Cmp(cert1.SerialNumber) != 0 || certs[1].SerialNumber.Cmp(cert2.SerialNumber) != 0 {
+			t.Fatalf("expected certs decoded in encoding order")
+		}
+	})
+}
+
+func TestValidateControlPlaneCerts(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(365*24*time.Hour)
+
+	root, rootKey := mustGenCACert(t, "root.linkerd.cluster.local", notBefore, notAfter)
+	intermediate, intermediateKey := mustSignCert(t, root, rootKey, "sub-ca.linkerd.cluster.local", notBefore, notAfter, true)
+	issuer, _ := mustSignCert(t, intermediate, intermediateKey, "identity.linkerd.cluster.local", notBefore, notAfter, true)
+
+	rootsPEM, err := encodeCertificatesPEM(root)
+	if err != nil {
+		t.Fatalf("error encoding roots: %s", err)
+	}
+	issuerChainPEM, err := encodeCertificatesPEM(issuer, intermediate)
+	if err != nil {
+		t.Fatalf("error encoding issuer chain: %s", err)
+	}
+
+	cpCerts := &pb.ControlPlaneCertsHA{
+		Roots:          &pb.CertData{Raw: []byte(rootsPEM)},
+		IssuerCrtChain: &pb.CertData{Raw: []byte(issuerChainPEM)},
+	}
+
+	diagnostics, err := ValidateControlPlaneCerts(cpCerts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(diagnostics.Roots) != 1 {
+		t.Fatalf("expected 1 root diagnostic, got %d", len(diagnostics.Roots))
+	}
+	if len(diagnostics.Issuers) != 2 {
+		t.Fatalf("expected 2 issuer diagnostics, got %d", len(diagnostics.Issuers))
+	}
+
+	// The leaf issuer only chains to the root through the intermediate
+	// that's also in IssuerCrtChain, so it's only trusted if diagnoseCert
+	// is given the rest of the chain as Intermediates.
+	leaf := diagnostics.Issuers[0]
+	if !leaf.TrustedByRoots {
+		t.Fatalf("expected the leaf issuer to be trusted via the intermediate in the chain, warnings: %v", leaf.Warnings)
+	}
+}
+
+func TestValidateControlPlaneCertsNilInput(t *testing.T) {
+	if _, err := ValidateControlPlaneCerts(nil); err == nil {
+		t.Fatalf("expected an error for nil input")
+	}
+}
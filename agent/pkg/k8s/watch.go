@@ -0,0 +1,129 @@
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// defaultCertPollInterval bounds how long we'll go without re-checking the
+// identity pod even if we don't observe any informer events for it. Trust
+// anchors rarely rotate, but issuer certs can rotate on the wire (e.g. under
+// cert-manager) without the pod itself being touched.
+const defaultCertPollInterval = 10 * time.Minute
+
+// WatchControlPlaneCerts watches the linkerd-identity pod via the shared pod
+// informer and emits a new *pb.ControlPlaneCertsHA on the returned channel
+// whenever the pod is (re)created, restarted, or its trust anchors env var
+// changes. It also re-polls every defaultCertPollInterval to catch issuer
+// rotation that doesn't touch the pod spec. The channel is closed and the
+// watch torn down when ctx is canceled; there is no separate Stop method.
+func (c *Client) WatchControlPlaneCerts(ctx context.Context) <-chan *pb.ControlPlaneCertsHA {
+	out := make(chan *pb.ControlPlaneCertsHA)
+
+	selector := labels.Set(map[string]string{
+		controlPlaneComponentLabel: identityComponentName,
+	}).AsSelector()
+
+	var lastHash [sha256.Size]byte
+	emit := func() {
+		cpCerts, err := c.GetControlPlaneCerts(ctx)
+		if err != nil {
+			c.log.Errorf("error getting control plane certs: %s", err)
+			return
+		}
+
+		hash := hashControlPlaneCerts(cpCerts)
+		if hash == lastHash {
+			return
+		}
+		lastHash = hash
+
+		select {
+		case out <- cpCerts:
+		case <-ctx.Done():
+		}
+	}
+
+	handler := cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			pod, ok := obj.(*v1.Pod)
+			if !ok {
+				return false
+			}
+			return selector.Matches(labels.Set(pod.Labels))
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { emit() },
+			UpdateFunc: func(interface{}, interface{}) { emit() },
+		},
+	}
+
+	informer := c.sharedInformers.Core().V1().Pods().Informer()
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		c.log.Errorf("error registering identity pod watch: %s", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(defaultCertPollInterval)
+		defer ticker.Stop()
+		defer close(out)
+		defer func() {
+			if registration != nil {
+				if err := informer.RemoveEventHandler(registration); err != nil {
+					c.log.Errorf("error removing identity pod watch: %s", err)
+				}
+			}
+		}()
+
+		// ctx.Done() is the Stop() for this watch: callers cancel ctx to
+		// tear down the event handler and stop the periodic re-poll.
+		for {
+			select {
+			case <-ticker.C:
+				emit()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// hashControlPlaneCerts returns a digest of cpCerts so callers can dedupe
+// events that don't actually change the served certs. It covers Divergent
+// and every replica's own roots/issuer chain, not just the flat
+// majority-selected fields: a replica drifting out of agreement with an
+// already-agreeing set can leave the majority pick byte-identical to the
+// last emitted value, and that's exactly the case WatchControlPlaneCerts
+// exists to surface.
+func hashControlPlaneCerts(cpCerts *pb.ControlPlaneCertsHA) [sha256.Size]byte {
+	h := sha256.New()
+
+	if cpCerts.Divergent {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+
+	for _, r := range cpCerts.Replicas {
+		h.Write([]byte(r.PodName))
+		if r.Roots != nil {
+			h.Write(r.Roots.Raw)
+		}
+		if r.IssuerChain != nil {
+			h.Write(r.IssuerChain.Raw)
+		}
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
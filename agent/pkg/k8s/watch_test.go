@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"testing"
+
+	pb "github.com/buoyantio/linkerd-buoyant/gen/bcloud"
+)
+
+func TestHashControlPlaneCerts(t *testing.T) {
+	replica := func(name, roots, chain string) *pb.ControlPlaneCertsReplica {
+		return &pb.ControlPlaneCertsReplica{
+			PodName:     name,
+			Roots:       &pb.CertData{Raw: []byte(roots)},
+			IssuerChain: &pb.CertData{Raw: []byte(chain)},
+		}
+	}
+
+	fixtures := []*struct {
+		testName string
+		a        *pb.ControlPlaneCertsHA
+		b        *pb.ControlPlaneCertsHA
+		wantSame bool
+	}{
+		{
+			"identical replicas and Divergent hash the same",
+			&pb.ControlPlaneCertsHA{Replicas: []*pb.ControlPlaneCertsReplica{replica("pod-1", "roots", "chain")}},
+			&pb.ControlPlaneCertsHA{Replicas: []*pb.ControlPlaneCertsReplica{replica("pod-1", "roots", "chain")}},
+			true,
+		},
+		{
+			"different Divergent hashes differently even with identical flat fields",
+			&pb.ControlPlaneCertsHA{Divergent: false, Replicas: []*pb.ControlPlaneCertsReplica{replica("pod-1", "roots", "chain")}},
+			&pb.ControlPlaneCertsHA{Divergent: true, Replicas: []*pb.ControlPlaneCertsReplica{replica("pod-1", "roots", "chain")}},
+			false,
+		},
+		{
+			"a replica drifting out of agreement hashes differently even if the majority pick is unchanged",
+			&pb.ControlPlaneCertsHA{Replicas: []*pb.ControlPlaneCertsReplica{
+				replica("pod-1", "roots", "chain-a"),
+				replica("pod-2", "roots", "chain-a"),
+			}},
+			&pb.ControlPlaneCertsHA{Replicas: []*pb.ControlPlaneCertsReplica{
+				replica("pod-1", "roots", "chain-a"),
+				replica("pod-2", "roots", "chain-b"),
+			}},
+			false,
+		},
+		{
+			"nil replicas don't panic",
+			&pb.ControlPlaneCertsHA{},
+			&pb.ControlPlaneCertsHA{},
+			true,
+		},
+	}
+
+	for _, tc := range fixtures {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			hashA := hashControlPlaneCerts(tc.a)
+			hashB := hashControlPlaneCerts(tc.b)
+
+			same := hashA == hashB
+			if same != tc.wantSame {
+				t.Fatalf("expected same=%v, got %v", tc.wantSame, same)
+			}
+		})
+	}
+}